@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config holds settings read from the environment at startup; README.md
+// lists every variable with its default
+type config struct {
+	// addr is HOST:PORT, defaulting to 0.0.0.0:8080
+	addr string
+	// corsOrigin is the allowed CORS origin from CORS_ORIGIN, defaulting to "*"
+	corsOrigin string
+	// requestTimeout is the per-request handler deadline from REQUEST_TIMEOUT, defaulting to 10s
+	requestTimeout time.Duration
+	// Server timeouts guard against slow clients (e.g. slowloris). Defaults:
+	// READ_HEADER_TIMEOUT 5s, READ_TIMEOUT 15s, WRITE_TIMEOUT 15s, IDLE_TIMEOUT 60s
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	// tlsCertFile and tlsKeyFile enable HTTPS when both TLS_CERT_FILE and TLS_KEY_FILE are set
+	tlsCertFile string
+	tlsKeyFile  string
+	// trustedProxies lists the TRUSTED_PROXIES addresses or CIDRs whose
+	// X-Forwarded-For header is believed; empty means it is ignored
+	trustedProxies []netip.Prefix
+	// logLevel and logFormat come from LOG_LEVEL (debug, info, warn, error)
+	// and LOG_FORMAT (text, json), defaulting to info and text
+	logLevel  slog.Level
+	logFormat string
+}
+
+// loadConfig reads and validates all environment variables, exiting with a
+// clear message listing every problem found
+func loadConfig() config {
+	var problems []string
+
+	port := getEnvDefault("PORT", "8080")
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be a number between 1 and 65535, got %q", port))
+	}
+
+	requestTimeout := getEnvDuration("REQUEST_TIMEOUT", 10*time.Second, &problems)
+	readHeaderTimeout := getEnvDuration("READ_HEADER_TIMEOUT", 5*time.Second, &problems)
+	readTimeout := getEnvDuration("READ_TIMEOUT", 15*time.Second, &problems)
+	writeTimeout := getEnvDuration("WRITE_TIMEOUT", 15*time.Second, &problems)
+	idleTimeout := getEnvDuration("IDLE_TIMEOUT", 60*time.Second, &problems)
+	// The timeout handler's 503 can only be sent while the connection is still writable
+	if requestTimeout >= writeTimeout {
+		problems = append(problems, fmt.Sprintf("REQUEST_TIMEOUT (%s) must be shorter than WRITE_TIMEOUT (%s)", requestTimeout, writeTimeout))
+	}
+
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	} else if tlsCertFile != "" {
+		// Load the pair now so a missing, unreadable or mismatched file fails
+		// at startup rather than inside ListenAndServeTLS
+		if _, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile); err != nil {
+			problems = append(problems, fmt.Sprintf("TLS_CERT_FILE/TLS_KEY_FILE could not be loaded: %v", err))
+		}
+	}
+
+	var trustedProxies []netip.Prefix
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES entry %q is not a valid CIDR", entry))
+				continue
+			}
+			trustedProxies = append(trustedProxies, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES entry %q is not a valid IP address", entry))
+			continue
+		}
+		addr = addr.Unmap()
+		trustedProxies = append(trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(getEnvDefault("LOG_LEVEL", "info"))); err != nil {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be one of debug, info, warn, error, got %q", os.Getenv("LOG_LEVEL")))
+	}
+	logFormat := strings.ToLower(getEnvDefault("LOG_FORMAT", "text"))
+	if logFormat != "text" && logFormat != "json" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be text or json, got %q", os.Getenv("LOG_FORMAT")))
+	}
+
+	cfg := config{
+		addr:              net.JoinHostPort(getEnvDefault("HOST", "0.0.0.0"), port),
+		corsOrigin:        getEnvDefault("CORS_ORIGIN", "*"),
+		requestTimeout:    requestTimeout,
+		readHeaderTimeout: readHeaderTimeout,
+		readTimeout:       readTimeout,
+		writeTimeout:      writeTimeout,
+		idleTimeout:       idleTimeout,
+		tlsCertFile:       tlsCertFile,
+		tlsKeyFile:        tlsKeyFile,
+		trustedProxies:    trustedProxies,
+		logLevel:          logLevel,
+		logFormat:         logFormat,
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			slog.Error("invalid configuration", "problem", p)
+		}
+		slog.Error("exiting due to invalid configuration", "problems", len(problems))
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// newLogger builds the process logger from the configured level and format
+func (c config) newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.logLevel}
+	if c.logFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// tlsEnabled reports whether a certificate and key were configured
+func (c config) tlsEnabled() bool {
+	return c.tlsCertFile != "" && c.tlsKeyFile != ""
+}
+
+// getEnvDefault returns the value of key, or def if it is unset or empty
+func getEnvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvDuration parses key as a positive Go duration, returning def when it
+// is unset and recording a problem when it is invalid
+func getEnvDuration(key string, def time.Duration, problems *[]string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		*problems = append(*problems, fmt.Sprintf("%s must be a positive duration like \"15s\", got %q", key, v))
+		return def
+	}
+	return d
+}
@@ -0,0 +1,18 @@
+package main
+
+import "net/http"
+
+// middlewareCORS adds CORS headers and answers preflight requests directly.
+// Other OPTIONS requests fall through to the mux like any other method.
+func (cfg *apiConfig) middlewareCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", cfg.corsOrigin)
+		w.Header().Set("Access-Control-Allow-Methods", cfg.corsAllowMethods)
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		if r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareCORS(t *testing.T) {
+	mux := http.NewServeMux()
+	routes := newRouter(mux)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	routes.handle(http.MethodGet, "/api/healthz", ok)
+	routes.handle(http.MethodPost, "/admin/reset", ok)
+	routes.handleMethodNotAllowed()
+	cfg := &apiConfig{corsOrigin: "*", corsAllowMethods: routes.allMethods()}
+	handler := cfg.middlewareCORS(mux)
+
+	if want := "GET, HEAD, POST, OPTIONS"; cfg.corsAllowMethods != want {
+		t.Errorf("corsAllowMethods = %q, want %q", cfg.corsAllowMethods, want)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		headers  map[string]string
+		wantCode int
+	}{
+		{
+			name:   "preflight",
+			target: "/api/healthz",
+			headers: map[string]string{
+				"Origin":                        "https://example.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "plain OPTIONS on known path",
+			target:   "/api/healthz",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "plain OPTIONS on unknown path",
+			target:   "/nope",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Origin without request method",
+			target:   "/api/healthz",
+			headers:  map[string]string{"Origin": "https://example.com"},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, tt.target, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != cfg.corsAllowMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, cfg.corsAllowMethods)
+			}
+		})
+	}
+}
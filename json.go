@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// respondWithJSON marshals payload and writes it with the given status code
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("marshalling JSON", "error", err)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Internal Server Error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+// errorCode is a machine-readable identifier included in error responses.
+// The Error.code enum in openapi.json must list every constant below;
+// TestOpenAPIErrorCodesMatchConstants checks that they agree.
+type errorCode string
+
+const (
+	errCodeInternal             errorCode = "INTERNAL_ERROR"
+	errCodeRateLimited          errorCode = "RATE_LIMITED"
+	errCodeConfirmationRequired errorCode = "CONFIRMATION_REQUIRED"
+	errCodeMethodNotAllowed     errorCode = "METHOD_NOT_ALLOWED"
+)
+
+// respondWithError writes a JSON error body of the form {"error": msg, "code": errCode}
+func respondWithError(w http.ResponseWriter, code int, errCode errorCode, msg string) {
+	type errorResponse struct {
+		Error string    `json:"error"`
+		Code  errorCode `json:"code"`
+	}
+	respondWithJSON(w, code, errorResponse{Error: msg, Code: errCode})
+}
@@ -1,42 +1,118 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"html"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // apiConfig holds our stateful, in-memory data for tracking metrics
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	rateLimiters   *rateLimiters
+	routeHits      *routeHits
+	statusClasses  statusClassCounts
+	corsOrigin     string
+	trustedProxies []netip.Prefix
+
+	// corsAllowMethods is derived from the registered routes
+	corsAllowMethods string
 }
 
 func main() {
 	const filepathRoot = "."
-	const port = "8080"
+	conf := loadConfig()
+	slog.SetDefault(conf.newLogger())
 
 	// Create an instance of apiConfig
-	apiCfg := &apiConfig{}
+	apiCfg := &apiConfig{
+		rateLimiters:   newRateLimiters(),
+		routeHits:      newRouteHits(),
+		corsOrigin:     conf.corsOrigin,
+		trustedProxies: conf.trustedProxies,
+	}
+	go apiCfg.rateLimiters.cleanup(time.Minute)
 
 	mux := http.NewServeMux()
+	routes := newRouter(mux)
 	
 	// Wrap the file server with our metrics middleware
 	fileServerHandler := http.FileServer(http.Dir(filepathRoot))
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", fileServerHandler)))
+	routes.handle(http.MethodGet, "/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", fileServerHandler)))
 	
 	// Add new routes for metrics and reset
-	mux.HandleFunc("GET /api/healthz", handlerReadiness)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	timeout := middlewareTimeout(conf.requestTimeout)
+	routes.handle(http.MethodGet, "/api/healthz", timeout(http.HandlerFunc(handlerReadiness)))
+	routes.handle(http.MethodGet, "/api/openapi.json", timeout(http.HandlerFunc(handlerOpenAPI)))
+	routes.handle(http.MethodGet, "/admin/metrics", timeout(http.HandlerFunc(apiCfg.handlerMetrics)))
+	routes.handle(http.MethodPost, "/admin/reset", timeout(http.HandlerFunc(apiCfg.handlerReset)))
+	routes.handle(http.MethodGet, "/metrics", timeout(promhttp.Handler()))
+
+	// Unsupported methods on any path above get a 405 listing the allowed ones
+	routes.handleMethodNotAllowed()
+	apiCfg.corsAllowMethods = routes.allMethods()
+
+	// Middleware is applied inside-out. Recovery sits just inside the logger so
+	// panic 500s are logged and counted; the outer one is a backstop for the
+	// logging and request ID layers themselves.
+	var handler http.Handler = mux
+	handler = middlewareCleanPath(handler)
+	handler = middlewareGzip(handler)
+	handler = apiCfg.middlewareRateLimit(handler)
+	handler = apiCfg.middlewareCORS(handler)
+	handler = middlewareRecover(handler)
+	handler = apiCfg.middlewareLog(handler)
+	handler = middlewareRequestID(handler)
+	handler = middlewareRecover(handler)
 
 	srv := &http.Server{
-		Addr:    "0.0.0.0:" + port,
-		Handler: mux,
+		Addr:              conf.addr,
+		Handler:           handler,
+		ReadHeaderTimeout: conf.readHeaderTimeout,
+		ReadTimeout:       conf.readTimeout,
+		WriteTimeout:      conf.writeTimeout,
+		IdleTimeout:       conf.idleTimeout,
 	}
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(srv.ListenAndServe())
+	// Stop accepting new requests on SIGINT/SIGTERM and let in-flight ones finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		var err error
+		if conf.tlsEnabled() {
+			slog.Info("serving files", "root", filepathRoot, "addr", conf.addr, "scheme", "https")
+			err = srv.ListenAndServeTLS(conf.tlsCertFile, conf.tlsKeyFile)
+		} else {
+			slog.Info("serving files", "root", filepathRoot, "addr", conf.addr, "scheme", "http")
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down gracefully")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown", "error", err)
+	}
 }
 
 func handlerReadiness(w http.ResponseWriter, r *http.Request) {
@@ -53,22 +129,59 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
-// handlerMetrics returns the metrics page as HTML
+// handlerMetrics returns the metrics page as HTML, or as JSON with ?format=json
 func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "json" {
+		respondWithJSON(w, http.StatusOK, struct {
+			FileserverHits int32            `json:"fileserver_hits"`
+			Routes         []routeHit       `json:"routes"`
+			StatusClasses  map[string]int64 `json:"status_classes"`
+		}{
+			FileserverHits: cfg.fileserverHits.Load(),
+			Routes:         cfg.routeHits.snapshot(),
+			StatusClasses:  cfg.statusClasses.snapshot(),
+		})
+		return
+	}
+
+	var routes strings.Builder
+	for _, rh := range cfg.routeHits.snapshot() {
+		fmt.Fprintf(&routes, "      <li>%s: %d</li>\n", html.EscapeString(rh.Route), rh.Hits)
+	}
+
+	var statuses strings.Builder
+	classes := cfg.statusClasses.snapshot()
+	for _, class := range []string{"2xx", "3xx", "4xx", "5xx"} {
+		fmt.Fprintf(&statuses, "      <li>%s: %d</li>\n", class, classes[class])
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	htmlTemplate := `<html>
   <body>
     <h1>Welcome, Chirpy Admin</h1>
     <p>Chirpy has been visited %d times!</p>
+    <h2>Requests per route</h2>
+    <ul>
+%s    </ul>
+    <h2>Responses by status</h2>
+    <ul>
+%s    </ul>
   </body>
 </html>`
-	w.Write([]byte(fmt.Sprintf(htmlTemplate, cfg.fileserverHits.Load())))
+	w.Write([]byte(fmt.Sprintf(htmlTemplate, cfg.fileserverHits.Load(), routes.String(), statuses.String())))
 }
 
-// handlerReset resets the fileserver hits counter to 0
+// handlerReset resets the fileserver hits, per-route and status counters to 0.
+// It requires ?confirm=true or an X-Confirm-Reset: true header.
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("confirm") != "true" && r.Header.Get("X-Confirm-Reset") != "true" {
+		respondWithError(w, http.StatusBadRequest, errCodeConfirmationRequired, "reset requires confirmation")
+		return
+	}
 	cfg.fileserverHits.Store(0)
+	cfg.routeHits.reset()
+	cfg.statusClasses.reset()
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits counter reset to 0"))
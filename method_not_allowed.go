@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// router registers method-specific routes on a mux and records which methods
+// each path supports, so Allow headers come from the same table as the routes
+type router struct {
+	mux     *http.ServeMux
+	methods map[string][]string
+	paths   []string
+}
+
+func newRouter(mux *http.ServeMux) *router {
+	return &router{mux: mux, methods: make(map[string][]string)}
+}
+
+// handle registers h for method on path
+func (rt *router) handle(method, path string, h http.Handler) {
+	rt.mux.Handle(method+" "+path, h)
+	if _, ok := rt.methods[path]; !ok {
+		rt.paths = append(rt.paths, path)
+	}
+	rt.methods[path] = append(rt.methods[path], method)
+	// The mux also serves HEAD from GET patterns
+	if method == http.MethodGet {
+		rt.methods[path] = append(rt.methods[path], http.MethodHead)
+	}
+}
+
+// allMethods returns every method registered on any path, in registration
+// order, followed by OPTIONS for CORS preflights
+func (rt *router) allMethods() string {
+	var methods []string
+	seen := map[string]bool{}
+	for _, path := range rt.paths {
+		for _, m := range rt.methods[path] {
+			if !seen[m] {
+				seen[m] = true
+				methods = append(methods, m)
+			}
+		}
+	}
+	return strings.Join(append(methods, http.MethodOptions), ", ")
+}
+
+// handleMethodNotAllowed registers a method-less catch-all for every known
+// path. These are less specific than the method patterns, so they only match
+// unsupported methods.
+func (rt *router) handleMethodNotAllowed() {
+	for _, path := range rt.paths {
+		rt.mux.Handle(path, handlerMethodNotAllowed(rt.methods[path]...))
+	}
+}
+
+// handlerMethodNotAllowed responds 405 with an Allow header listing the
+// methods that are registered for the path
+func handlerMethodNotAllowed(allowed ...string) http.Handler {
+	allow := strings.Join(allowed, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		respondWithError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
+	})
+}
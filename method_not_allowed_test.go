@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	mux := http.NewServeMux()
+	routes := newRouter(mux)
+	called := false
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	routes.handle(http.MethodGet, "/app/", ok)
+	routes.handle(http.MethodGet, "/admin/thing", ok)
+	routes.handle(http.MethodPost, "/admin/thing", ok)
+	routes.handleMethodNotAllowed()
+
+	tests := []struct {
+		method, target string
+		wantCode       int
+		wantAllow      string
+	}{
+		{"GET", "/app/index.html", http.StatusOK, ""},
+		{"HEAD", "/app/index.html", http.StatusOK, ""},
+		{"DELETE", "/app/index.html", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{"POST", "/app/", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{"POST", "/admin/thing", http.StatusOK, ""},
+		{"PUT", "/admin/thing", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			called = false
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if wantCalled := tt.wantCode == http.StatusOK; called != wantCalled {
+				t.Errorf("handler called = %v, want %v", called, wantCalled)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// middlewareCleanPath canonicalizes trailing-slash paths: GET and HEAD are
+// redirected with a 301, other methods are routed as if the slash were absent.
+// The /app/ file server is left alone since directories need the trailing slash.
+func middlewareCleanPath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p == "/" || !strings.HasSuffix(p, "/") || strings.HasPrefix(p, "/app/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Work on the escaped form so that e.g. %3F stays part of the path
+		clean := canonicalPath(r.URL.EscapedPath())
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			target := clean
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		unescaped, err := url.PathUnescape(clean)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = unescaped
+		r2.URL.RawPath = clean
+		next.ServeHTTP(w, r2)
+		// Carry the matched route back so middlewareLog can count it
+		r.Pattern = r2.Pattern
+	})
+}
+
+// canonicalPath cleans an escaped path and collapses any leading run of '/'
+// and '\' to a single '/', so the result can never be read by a browser as a
+// scheme-relative ("//host") or backslash ("/\host") off-site URL
+func canonicalPath(escaped string) string {
+	return "/" + strings.TrimLeft(path.Clean(escaped), `/\`)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareCleanPathRedirects(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{name: "trailing slash", target: "/api/healthz/", want: "/api/healthz"},
+		{name: "query is kept", target: "/api/healthz/?x=1", want: "/api/healthz?x=1"},
+		{name: "scheme-relative host", target: "//evil.com/", want: "/evil.com"},
+		// EscapedPath encodes a raw backslash as %5C, which browsers treat as a path byte
+		{name: "backslash host", target: `/\evil.com/`, want: "/%5Cevil.com"},
+		{name: "mixed leading separators", target: `//\/evil.com/`, want: "/%5C/evil.com"},
+		{name: "escaped question mark", target: "/api/a%3Fb/", want: "/api/a%3Fb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := middlewareCleanPath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			if called {
+				t.Fatal("next handler was called instead of redirecting")
+			}
+			if w.Code != http.StatusMovedPermanently {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusMovedPermanently)
+			}
+			loc := w.Header().Get("Location")
+			if loc != tt.want {
+				t.Errorf("Location = %q, want %q", loc, tt.want)
+			}
+			if strings.HasPrefix(loc, "//") || strings.HasPrefix(loc, `/\`) {
+				t.Errorf("Location %q would be treated as off-site", loc)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCleanPathAppPassthrough(t *testing.T) {
+	var gotPath string
+	h := middlewareCleanPath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/app/assets/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotPath != "/app/assets/" {
+		t.Errorf("path = %q, want %q", gotPath, "/app/assets/")
+	}
+}
+
+func TestMiddlewareCleanPathRewritesPostInPlace(t *testing.T) {
+	mux := http.NewServeMux()
+	var gotPath string
+	mux.HandleFunc("POST /admin/reset", func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	})
+	r := httptest.NewRequest("POST", "/admin/reset/", nil)
+	w := httptest.NewRecorder()
+	middlewareCleanPath(mux).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotPath != "/admin/reset" {
+		t.Errorf("path = %q, want %q", gotPath, "/admin/reset")
+	}
+	if r.Pattern != "POST /admin/reset" {
+		t.Errorf("Pattern = %q, want it carried back to the original request", r.Pattern)
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+	tests := map[string]string{
+		"/api/healthz/": "/api/healthz",
+		"//evil.com/":   "/evil.com",
+		`/\evil.com`:    "/evil.com",
+		`\\/\evil.com`:  "/evil.com",
+		"/a/../b/":      "/b",
+		"//":            "/",
+	}
+	for in, want := range tests {
+		if got := canonicalPath(in); got != want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
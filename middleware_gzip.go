@@ -0,0 +1,133 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the smallest response body worth compressing
+const gzipMinSize = 1024
+
+// gzipResponseWriter buffers the start of a response so it can decide
+// whether compression is worthwhile before any headers are sent
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	decided    bool
+	buf        []byte
+	gz         *gzip.Writer
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.decided {
+		return
+	}
+	gw.statusCode = code
+}
+
+func (gw *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !gw.decided {
+		gw.buf = append(gw.buf, p...)
+		if len(gw.buf) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := gw.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(p)
+	}
+	return gw.ResponseWriter.Write(p)
+}
+
+// decide sends headers, choosing compression only for large, compressible
+// responses, then writes out whatever has been buffered so far
+func (gw *gzipResponseWriter) decide() error {
+	gw.decided = true
+	h := gw.Header()
+	if h.Get("Content-Type") == "" && len(gw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(gw.buf))
+	}
+
+	compress := len(gw.buf) >= gzipMinSize &&
+		gw.statusCode != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" &&
+		!isCompressedContentType(h.Get("Content-Type"))
+
+	if !compress {
+		gw.ResponseWriter.WriteHeader(gw.statusCode)
+		_, err := gw.ResponseWriter.Write(gw.buf)
+		gw.buf = nil
+		return err
+	}
+
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	gw.ResponseWriter.WriteHeader(gw.statusCode)
+	gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	_, err := gw.gz.Write(gw.buf)
+	gw.buf = nil
+	return err
+}
+
+// close flushes any buffered output and finishes the gzip stream
+func (gw *gzipResponseWriter) close() error {
+	if !gw.decided {
+		if err := gw.decide(); err != nil {
+			return err
+		}
+	}
+	if gw.gz != nil {
+		return gw.gz.Close()
+	}
+	return nil
+}
+
+// isCompressedContentType reports whether content of this type is already compressed
+func isCompressedContentType(contentType string) bool {
+	switch {
+	case strings.HasPrefix(contentType, "image/svg+xml"):
+		return false
+	case strings.HasPrefix(contentType, "image/"),
+		strings.HasPrefix(contentType, "video/"),
+		strings.HasPrefix(contentType, "audio/"),
+		strings.HasPrefix(contentType, "application/zip"),
+		strings.HasPrefix(contentType, "application/gzip"),
+		strings.HasPrefix(contentType, "application/x-gzip"):
+		return true
+	}
+	return false
+}
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, _, _ := strings.Cut(enc, ";")
+		if strings.TrimSpace(name) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareGzip compresses responses for clients that accept gzip
+func middlewareGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(gw, r)
+		// Not deferred: if next panics before anything is sent, the buffer is
+		// dropped so middlewareRecover can still write its 500. If gzip output
+		// has already started, middlewareRecover aborts the connection so the
+		// client sees a truncated stream rather than a corrupt body.
+		gw.close()
+	})
+}
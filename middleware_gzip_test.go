@@ -0,0 +1,167 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func serveGzip(t *testing.T, method string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	middlewareGzip(h).ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareGzipSmallResponsePassesThrough(t *testing.T) {
+	w := serveGzip(t, "GET", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding = %q, want none", enc)
+	}
+	if w.Body.String() != "OK" {
+		t.Errorf("body = %q, want %q", w.Body.String(), "OK")
+	}
+}
+
+func TestMiddlewareGzipCompressesLargeResponse(t *testing.T) {
+	body := strings.Repeat("chirp ", gzipMinSize)
+	w := serveGzip(t, "GET", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(body))
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("Content-Length = %q, want it removed", cl)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Error("decompressed body does not match original")
+	}
+}
+
+func TestMiddlewareGzipSkips(t *testing.T) {
+	body := strings.Repeat("x", 2*gzipMinSize)
+	tests := []struct {
+		name   string
+		method string
+		h      http.HandlerFunc
+	}{
+		{
+			name:   "HEAD",
+			method: "HEAD",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			},
+		},
+		{
+			name:   "partial content",
+			method: "GET",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", "bytes 0-2047/4096")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(body))
+			},
+		},
+		{
+			name:   "already encoded",
+			method: "GET",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(body))
+			},
+		},
+		{
+			name:   "compressed content type",
+			method: "GET",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte(body))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveGzip(t, tt.method, tt.h)
+			if enc := w.Header().Get("Content-Encoding"); enc == "gzip" {
+				t.Error("response was gzip-compressed")
+			}
+			if tt.method != "HEAD" && w.Body.String() != body {
+				t.Error("body was altered")
+			}
+		})
+	}
+}
+
+func TestMiddlewareGzipPanicLeavesResponseToRecover(t *testing.T) {
+	h := middlewareRecover(middlewareGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	})))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Body.String(), "partial") {
+		t.Error("buffered output from the panicking handler was sent")
+	}
+}
+
+func TestMiddlewareGzipPanicAfterCompressingAbortsResponse(t *testing.T) {
+	srv := httptest.NewServer(middlewareRecover(middlewareGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Incompressible and large enough that gzip output reaches the client
+		body := make([]byte, 256*gzipMinSize)
+		rand.New(rand.NewSource(1)).Read(body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+		panic("boom")
+	}))))
+	defer srv.Close()
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", resp.Header.Get("Content-Encoding"))
+	}
+	// A cleanly finished response here would mean the 500 was appended to
+	// the gzip stream; the transfer itself must fail instead
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("read a complete response (%d bytes), want the connection aborted", len(body))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// responseWriter wraps http.ResponseWriter to record the status code that
+// was actually sent: the first WriteHeader, or an implicit 200 on Write
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// middlewareLog logs the method, path, status code and duration of each request
+// and counts hits per matched route pattern and per status class
+func (cfg *apiConfig) middlewareLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		// Deferred so requests aborted with http.ErrAbortHandler are still recorded
+		defer func() {
+			// The mux fills in r.Pattern once it has matched a route
+			if r.Pattern != "" {
+				cfg.routeHits.inc(r.Pattern)
+			}
+			cfg.statusClasses.inc(rw.statusCode)
+			duration := time.Since(start)
+			observeRequest(r.Method, r.Pattern, rw.statusCode, duration)
+			slog.Info("request",
+				"request_id", requestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rw.statusCode,
+				"duration", duration,
+			)
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareLogRecordsSentStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		h     http.HandlerFunc
+		class string
+	}{
+		{
+			name: "later WriteHeader is ignored",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			class: "4xx",
+		},
+		{
+			name: "Write before WriteHeader is an implicit 200",
+			h: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("OK"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			class: "2xx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{routeHits: newRouteHits()}
+			w := httptest.NewRecorder()
+			cfg.middlewareLog(tt.h).ServeHTTP(w, httptest.NewRequest("GET", "/api/healthz", nil))
+
+			classes := cfg.statusClasses.snapshot()
+			if classes[tt.class] != 1 {
+				t.Errorf("status classes = %v, want one %s", classes, tt.class)
+			}
+		})
+	}
+}
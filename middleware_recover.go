@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// startedWriter records whether any part of the response has been sent
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (sw *startedWriter) WriteHeader(code int) {
+	sw.started = true
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *startedWriter) Write(b []byte) (int, error) {
+	sw.started = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// middlewareRecover turns a panic in any handler into a 500 instead of crashing
+// the server. If the response has already started, a 500 can no longer be sent
+// cleanly, so the connection is aborted instead.
+func middlewareRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &startedWriter{ResponseWriter: w}
+		defer func() {
+			if err := recover(); err != nil {
+				// ErrAbortHandler is the sanctioned way to abort a response; let net/http handle it
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				slog.Error("panic serving request",
+					"request_id", requestIDFromContext(r.Context()),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"panic", err,
+					"response_started", sw.started,
+					"stack", string(debug.Stack()),
+				)
+				if sw.started {
+					panic(http.ErrAbortHandler)
+				}
+				respondWithError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
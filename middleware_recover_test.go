@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareRecoverIsLoggedAndCounted(t *testing.T) {
+	cfg := &apiConfig{routeHits: newRouteHits()}
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := middlewareRequestID(cfg.middlewareLog(middlewareRecover(panicking)))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/healthz", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w.Header().Get(requestIDHeader) == "" {
+		t.Error("missing X-Request-ID on panic response")
+	}
+	if got := cfg.statusClasses.snapshot()["5xx"]; got != 1 {
+		t.Errorf("5xx count = %d, want 1", got)
+	}
+}
+
+func TestMiddlewareRecoverAbortsStartedResponse(t *testing.T) {
+	handler := middlewareRecover(middlewareGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 4*gzipMinSize)))
+		panic("boom")
+	})))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("panic = %v, want http.ErrAbortHandler", p)
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d, want the already-sent %d", w.Code, http.StatusOK)
+		}
+		if strings.Contains(w.Body.String(), string(errCodeInternal)) {
+			t.Error("500 error body was appended to a started response")
+		}
+	}()
+	handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// middlewareTimeout aborts handlers that run longer than d with a 503.
+// http.TimeoutHandler buffers the response and does not support flushing,
+// so it is applied per route rather than around the whole mux; streaming
+// routes and the file server are left unwrapped.
+func middlewareTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, "request timeout")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chirpy_http_requests_total",
+		Help: "Total HTTP requests by method, route and status code.",
+	}, []string{"method", "path", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chirpy_http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+)
+
+// pathLabel turns a mux pattern such as "GET /api/chirps" into the path
+// label "/api/chirps"; the method already has its own label
+func pathLabel(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// observeRequest records a finished request. pattern is the matched mux
+// route, which keeps label cardinality bounded; unmatched requests share one label.
+func observeRequest(method, pattern string, status int, d time.Duration) {
+	path := pathLabel(pattern)
+	httpRequestsTotal.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, path).Observe(d.Seconds())
+}
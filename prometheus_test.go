@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestPathLabel(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"GET /api/healthz", "/api/healthz"},
+		{"POST /admin/reset", "/admin/reset"},
+		{"/app/", "/app/"},
+		{"", "unmatched"},
+	}
+	for _, tt := range tests {
+		if got := pathLabel(tt.pattern); got != tt.want {
+			t.Errorf("pathLabel(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
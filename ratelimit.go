@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitPerSecond = 10
+	rateLimitBurst     = 20
+	rateLimitStaleAge  = 3 * time.Minute
+)
+
+// clientLimiter is a token bucket for a single client IP
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiters holds one token bucket per client IP
+type rateLimiters struct {
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+}
+
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{clients: make(map[string]*clientLimiter)}
+}
+
+// get returns the limiter for ip, creating it on first use
+func (rl *rateLimiters) get(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	c, ok := rl.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rateLimitPerSecond, rateLimitBurst)}
+		rl.clients[ip] = c
+	}
+	c.lastSeen = time.Now()
+	return c.limiter
+}
+
+// cleanup periodically drops limiters for clients that have gone quiet
+func (rl *rateLimiters) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rl.mu.Lock()
+		for ip, c := range rl.clients {
+			if time.Since(c.lastSeen) > rateLimitStaleAge {
+				delete(rl.clients, ip)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
+
+// clientIP returns the IP to rate limit on. X-Forwarded-For is only honored
+// when the direct peer is a trusted proxy, and then the right-most hop that
+// isn't a trusted proxy is used, since anything left of it is client-supplied.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !cfg.isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !cfg.isTrustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+// isTrustedProxy reports whether ip falls within one of the TRUSTED_PROXIES ranges
+func (cfg *apiConfig) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range cfg.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareRateLimit rejects clients that exceed their per-IP request budget
+func (cfg *apiConfig) middlewareRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.rateLimiters.get(cfg.clientIP(r)).Allow() {
+			w.Header().Set("Retry-After", "1")
+			respondWithError(w, http.StatusTooManyRequests, errCodeRateLimited, "Too Many Requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{
+			name:       "no proxies configured ignores XFF",
+			remoteAddr: "203.0.113.5:1234",
+			xff:        []string{"198.51.100.9"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted peer ignores XFF",
+			trusted:    trusted,
+			remoteAddr: "203.0.113.5:1234",
+			xff:        []string{"198.51.100.9"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted peer uses XFF",
+			trusted:    trusted,
+			remoteAddr: "10.1.2.3:1234",
+			xff:        []string{"198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "spoofed left-most hops are skipped",
+			trusted:    trusted,
+			remoteAddr: "10.1.2.3:1234",
+			xff:        []string{"1.1.1.1, 2.2.2.2, 198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "trusted hops are walked past",
+			trusted:    trusted,
+			remoteAddr: "10.1.2.3:1234",
+			xff:        []string{"198.51.100.9, 192.0.2.1", "10.9.9.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "all hops trusted falls back to left-most",
+			trusted:    trusted,
+			remoteAddr: "10.1.2.3:1234",
+			xff:        []string{"10.4.4.4, 10.5.5.5"},
+			want:       "10.4.4.4",
+		},
+		{
+			name:       "trusted peer without XFF",
+			trusted:    trusted,
+			remoteAddr: "10.1.2.3:1234",
+			want:       "10.1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{trustedProxies: tt.trusted}
+			r := httptest.NewRequest("GET", "/api/healthz", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := cfg.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
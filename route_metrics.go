@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// routeHits counts requests per registered route pattern
+type routeHits struct {
+	mu     sync.Mutex
+	counts map[string]*atomic.Int64
+}
+
+func newRouteHits() *routeHits {
+	return &routeHits{counts: make(map[string]*atomic.Int64)}
+}
+
+// inc increments the counter for pattern, creating it on first use
+func (rh *routeHits) inc(pattern string) {
+	rh.mu.Lock()
+	c, ok := rh.counts[pattern]
+	if !ok {
+		c = &atomic.Int64{}
+		rh.counts[pattern] = c
+	}
+	rh.mu.Unlock()
+	c.Add(1)
+}
+
+// routeHit is a single route's request count
+type routeHit struct {
+	Route string `json:"route"`
+	Hits  int64  `json:"hits"`
+}
+
+// snapshot returns the current counts sorted by route
+func (rh *routeHits) snapshot() []routeHit {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	hits := make([]routeHit, 0, len(rh.counts))
+	for route, c := range rh.counts {
+		hits = append(hits, routeHit{Route: route, Hits: c.Load()})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Route < hits[j].Route })
+	return hits
+}
+
+// reset zeroes all route counters
+func (rh *routeHits) reset() {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	rh.counts = make(map[string]*atomic.Int64)
+}
+
+// statusClassCounts counts responses by status class (2xx through 5xx)
+type statusClassCounts struct {
+	counts [4]atomic.Int64
+}
+
+// inc records a response with the given status code; 1xx codes are ignored
+func (sc *statusClassCounts) inc(code int) {
+	if class := code / 100; class >= 2 && class <= 5 {
+		sc.counts[class-2].Add(1)
+	}
+}
+
+// snapshot returns the counts keyed by class label, e.g. "4xx"
+func (sc *statusClassCounts) snapshot() map[string]int64 {
+	out := make(map[string]int64, len(sc.counts))
+	for i := range sc.counts {
+		out[fmt.Sprintf("%dxx", i+2)] = sc.counts[i].Load()
+	}
+	return out
+}
+
+// reset zeroes all status class counters
+func (sc *statusClassCounts) reset() {
+	for i := range sc.counts {
+		sc.counts[i].Store(0)
+	}
+}